@@ -0,0 +1,281 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"time"
+)
+
+func numCPU() int { return runtime.NumCPU() }
+
+// ProcessInfo holds the process state that is meaningful on every OS ps
+// supports. Anything that only exists on one platform lives in that
+// platform's extra struct instead (see process.extra in ps_linux.go,
+// ps_darwin.go and ps_freebsd.go).
+type ProcessInfo struct {
+	Pid       string // process id
+	Ppid      string // process id of the parent process
+	Pgrp      string // process group id
+	Sid       string // session id
+	Uid       string // owner of the process
+	State     string // R is running, S is sleeping, D is uninterruptible sleep, Z is zombie, T is traced or stopped
+	StartTime string // time the process started, in an OS-specific unit
+	Utime     string // time spent in user mode
+	Stime     string // time spent in kernel mode
+	Rss       string // resident set size
+	Vsize     string // virtual memory size
+	Ctty      string // controlling tty, or "?" if none
+	Cmd       string // filename of the executable
+	Args      string // full command line, only populated when requested
+}
+
+// Process is a single process as reported by a ProcessSource. It carries
+// the OS-independent ProcessInfo plus whatever extra, platform-specific
+// data the backend collected for it.
+type Process struct {
+	ProcessInfo
+	extra interface{}
+
+	// deltaPcpu is the %CPU computed by ProcessTable.Refresh from the
+	// change in CPU time between two samples, rather than the lifetime
+	// average cpuPercent computes. Empty until a Refresh has a prior
+	// sample to compare against.
+	deltaPcpu string
+}
+
+// ProcessSource abstracts how ps learns about the processes on the machine.
+// Every OS ps runs on provides one, and ps itself never looks at /proc,
+// sysctl, or any other OS detail directly - it only talks to this
+// interface.
+type ProcessSource interface {
+	// List returns every process currently visible to ps.
+	List() ([]Process, error)
+	// Read returns the single process identified by pid.
+	Read(pid string) (Process, error)
+	// Namespaces returns the namespace ids (net, pid, mnt, ...) that pid
+	// belongs to, keyed by namespace name. OSes without a concept of
+	// namespaces return an empty map.
+	Namespaces(pid string) (map[string]string, error)
+}
+
+// ProcessTable is the full set of processes ps knows about, plus the
+// terminal geometry used to lay out columns.
+type ProcessTable struct {
+	table    []Process
+	maxwidth int
+	source   ProcessSource
+
+	// prevJiffies and prevUptime are the previous Refresh's samples,
+	// used to turn cumulative CPU time into a delta %CPU. prevJiffies
+	// is keyed by pid; a pid's entry is only trusted if its StartTime
+	// still matches, so a reused pid doesn't get charged for a dead
+	// process's CPU time.
+	prevJiffies map[string]cpuSample
+	prevUptime  float64
+}
+
+// cpuSample is one process's CPU-time sample, taken at a known point in
+// wall-clock time.
+type cpuSample struct {
+	startTime string
+	jiffies   int
+}
+
+// LoadTable asks the platform ProcessSource to enumerate every process and
+// records the result, along with the current terminal width. It does not
+// compute a delta %CPU; use Refresh for that.
+func (pT *ProcessTable) LoadTable() error {
+	if pT.source == nil {
+		pT.source = newProcessSource()
+	}
+
+	table, err := pT.source.List()
+	if err != nil {
+		return err
+	}
+	pT.table = table
+
+	_, columnSize := terminalSize()
+	pT.maxwidth = columnSize
+
+	return nil
+}
+
+// Refresh re-lists every process and, for any pid it also saw last
+// Refresh (and whose StartTime hasn't changed, i.e. it's not a reused
+// pid), computes a %CPU from the change in CPU time over the elapsed
+// wall-clock time - the same math top(1) uses, rather than LoadTable's
+// lifetime-average %CPU.
+func (pT *ProcessTable) Refresh() error {
+	if pT.source == nil {
+		pT.source = newProcessSource()
+	}
+
+	table, err := pT.source.List()
+	if err != nil {
+		return err
+	}
+
+	uptime, uerr := systemUptime()
+	elapsed := uptime - pT.prevUptime
+	ncpu := float64(numCPU())
+
+	next := make(map[string]cpuSample, len(table))
+	for i := range table {
+		p := &table[i]
+		jiffies := atoi(p.Utime) + atoi(p.Stime)
+
+		if prev, ok := pT.prevJiffies[p.Pid]; ok && uerr == nil && elapsed > 0 && prev.startTime == p.StartTime {
+			p.deltaPcpu = deltaPcpuPct(jiffies-prev.jiffies, elapsed, ncpu)
+		}
+
+		next[p.Pid] = cpuSample{startTime: p.StartTime, jiffies: jiffies}
+	}
+
+	pT.table = table
+	pT.prevJiffies = next
+	pT.prevUptime = uptime
+
+	_, columnSize := terminalSize()
+	pT.maxwidth = columnSize
+
+	return nil
+}
+
+// deltaPcpuPct computes the %CPU a process used over the last sampling
+// interval: deltaJiffies of CPU time (clamped to non-negative, since a
+// reused counter or clock skew could otherwise drive it below zero) spent
+// across elapsed wall-clock seconds, spread over ncpu CPUs.
+func deltaPcpuPct(deltaJiffies int, elapsed, ncpu float64) string {
+	if deltaJiffies < 0 {
+		deltaJiffies = 0
+	}
+	pct := float64(deltaJiffies) / userHZ / (elapsed * ncpu) * 100
+	return fmt.Sprintf("%.1f", pct)
+}
+
+var flags struct {
+	x         bool
+	o         string
+	O         string
+	pid       string
+	container string
+	forest    bool
+	sort      string
+	watch     float64
+}
+
+// newContainerProcessSource and resolveContainerPid are overridden by
+// ps_container_linux.go's init(), the only platform that can join another
+// process's namespaces. Elsewhere --pid/--container report that they
+// aren't supported.
+var newContainerProcessSource = func(targetPid string) (ProcessSource, error) {
+	return nil, fmt.Errorf("ps: --pid is not supported on this OS")
+}
+
+var resolveContainerPid = func(id string) (string, error) {
+	return "", fmt.Errorf("ps: --container is not supported on this OS")
+}
+
+func main() {
+	flag.BoolVar(&flags.x, "x", false, "show the full command line")
+	flag.StringVar(&flags.o, "o", "", "comma-separated list of columns to display (e.g. pid,user,%cpu,rss,comm), or json/csv for structured output")
+	flag.StringVar(&flags.O, "O", "", "comma-separated list of columns to add to the default set")
+	flag.StringVar(&flags.pid, "pid", "", "list the processes visible from this pid's namespaces")
+	flag.StringVar(&flags.container, "container", "", "list the processes running inside this container id")
+	flag.BoolVar(&flags.forest, "forest", false, "show the process tree using box-drawing connectors")
+	flag.BoolVar(&flags.forest, "f", false, "shorthand for -forest")
+	flag.StringVar(&flags.sort, "sort", "pid", "sibling order in -forest: pid, start or cpu")
+	flag.Float64Var(&flags.watch, "watch", 0, "repaint every N seconds, top(1)-style, instead of printing once")
+	flag.Float64Var(&flags.watch, "t", 0, "shorthand for -watch")
+	flag.Parse()
+
+	pT := &ProcessTable{}
+	if flags.container != "" {
+		pid, err := resolveContainerPid(flags.container)
+		if err != nil {
+			log.Fatal(err)
+		}
+		flags.pid = pid
+	}
+	if flags.pid != "" {
+		src, err := newContainerProcessSource(flags.pid)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pT.source = src
+	}
+
+	descs, err := columnsToShow()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flags.watch > 0 {
+		watch(pT, descs, time.Duration(flags.watch*float64(time.Second)))
+		return
+	}
+
+	if err := pT.LoadTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	if flags.forest {
+		root := flags.pid
+		if root == "" {
+			root = "1"
+		}
+		printForest(buildForest(pT.table, root, flags.sort), pT.maxwidth)
+		return
+	}
+
+	if isStructuredFormat(flags.o) {
+		var err error
+		if flags.o == "json" {
+			err = writeJSON(os.Stdout, pT.table, descs)
+		} else {
+			err = writeCSV(os.Stdout, pT.table, descs)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	printTable(pT, descs)
+}
+
+// columnsToShow resolves -o/-O into the Descriptors to print. -o json and
+// -o csv name an output mode rather than a column list, so they report the
+// default columns (plus whatever -O adds) and let main's structured-output
+// branch pick the encoding.
+func columnsToShow() ([]Descriptor, error) {
+	switch {
+	case isStructuredFormat(flags.o):
+		if flags.O != "" {
+			extra, err := parseFormat(flags.O)
+			if err != nil {
+				return nil, err
+			}
+			return append(defaultDescriptors(), extra...), nil
+		}
+		return defaultDescriptors(), nil
+	case flags.o != "":
+		return parseFormat(flags.o)
+	case flags.O != "":
+		extra, err := parseFormat(flags.O)
+		if err != nil {
+			return nil, err
+		}
+		return append(defaultDescriptors(), extra...), nil
+	default:
+		return defaultDescriptors(), nil
+	}
+}
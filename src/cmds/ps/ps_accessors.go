@@ -0,0 +1,69 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strconv"
+
+// The accessors below give -o json/csv (ps_struct.go) typed values for the
+// columns that are numeric in every ps implementation, instead of the
+// plain-string fields ProcessInfo stores everything else in. Each one
+// parses leniently: a field that isn't a valid number (rare, but possible
+// on an OS that doesn't populate it) reports 0 rather than erroring, the
+// same way the string columns report "?" instead of failing.
+
+// PidInt returns Pid as an integer.
+func (p *Process) PidInt() int { return atoi(p.Pid) }
+
+// PpidInt returns Ppid as an integer.
+func (p *Process) PpidInt() int { return atoi(p.Ppid) }
+
+// UtimeInt returns Utime, in jiffies, as an integer.
+func (p *Process) UtimeInt() int { return atoi(p.Utime) }
+
+// StimeInt returns Stime, in jiffies, as an integer.
+func (p *Process) StimeInt() int { return atoi(p.Stime) }
+
+// NiceInt returns the process's nice value as an integer.
+func (p *Process) NiceInt() int { return atoi(p.Nice()) }
+
+// PriInt returns the process's scheduling priority as an integer.
+func (p *Process) PriInt() int { return atoi(p.Priority()) }
+
+// RssKB returns the process's resident set size in KiB, the same unit and
+// value the rss column's Extract (ps_format.go) prints, so -o json and
+// -o csv/the text table agree on this column.
+func (p *Process) RssKB() uint64 {
+	pages, err := strconv.ParseUint(p.Rss, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(pageSizeBytes()) / 1024
+}
+
+// VszKB returns the process's virtual memory size in KiB, the same unit
+// and value the vsz column's Extract (ps_format.go) prints, so -o json
+// and -o csv/the text table agree on this column.
+func (p *Process) VszKB() uint64 {
+	vsz, err := strconv.ParseUint(p.Vsize, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return vsz / 1024
+}
+
+// CPUPercent returns %CPU as a float64, preferring the delta value
+// ProcessTable.Refresh computed the same way pcpuColumn does.
+func (p *Process) CPUPercent() float64 {
+	s := pcpuColumn(p)
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// MemPercent returns %MEM as a float64.
+func (p *Process) MemPercent() float64 {
+	s := memPercent(*p)
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUidMap(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data string
+		want []uidMapEntry
+	}{
+		{
+			name: "single entry",
+			data: "0 100000 65536\n",
+			want: []uidMapEntry{{containerID: 0, hostID: 100000, length: 65536}},
+		},
+		{
+			name: "multiple entries",
+			data: "0 100000 1\n1000 1000 1\n",
+			want: []uidMapEntry{
+				{containerID: 0, hostID: 100000, length: 1},
+				{containerID: 1000, hostID: 1000, length: 1},
+			},
+		},
+		{
+			name: "malformed lines are skipped",
+			data: "0 100000 65536\nbogus line here\n",
+			want: []uidMapEntry{{containerID: 0, hostID: 100000, length: 65536}},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseUidMap(tt.data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUidMap(%q) = %+v, want %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateUid(t *testing.T) {
+	uidMap := []uidMapEntry{
+		{containerID: 0, hostID: 100000, length: 65536},
+	}
+
+	for _, tt := range []struct {
+		name string
+		uid  string
+		want string
+	}{
+		{name: "uid within mapped range", uid: "0", want: "100000"},
+		{name: "uid in the middle of the range", uid: "1000", want: "101000"},
+		{name: "uid outside the mapped range is unchanged", uid: "70000", want: "70000"},
+		{name: "non-numeric uid is unchanged", uid: "nobody", want: "nobody"},
+		{name: "no map at all leaves uid unchanged", uid: "0", want: "0"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := uidMap
+			if tt.name == "no map at all leaves uid unchanged" {
+				m = nil
+			}
+			if got := translateUid(tt.uid, m); got != tt.want {
+				t.Errorf("translateUid(%q) = %q, want %q", tt.uid, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// userHZ is the kernel clock tick rate jiffies/utime/stime are counted in.
+// It is a Linux concept; on other OSes Utime/Stime are simply empty and the
+// CPU-derived columns below report "?".
+const userHZ = 100
+
+// Descriptor describes one selectable ps column: how to extract it from a
+// Process, what to print as its header, and how much room to give it. This
+// mirrors POSIX ps's -o format and the descriptor registries used by tools
+// like psgo.
+type Descriptor struct {
+	// Key is the descriptor's registry name (e.g. "pcpu"), filled in
+	// automatically to match its key in the descriptors map. It's the
+	// field name -o json/csv use, since "%cpu" isn't a great JSON key.
+	Key     string
+	Header  string
+	Extract func(*Process) string
+	Width   int
+	// Numeric returns this column as a Go number instead of a string,
+	// for descriptors where that's meaningful (pid, rss, pcpu, ...).
+	// -o json uses it so those fields marshal as JSON numbers; it's nil
+	// for columns like comm or tty that don't have a numeric form.
+	Numeric func(*Process) interface{}
+}
+
+// descriptors is the registry of every column ps knows how to print,
+// keyed by its short -o name.
+var descriptors = map[string]Descriptor{
+	"pid":   {Header: "PID", Extract: func(p *Process) string { return p.Pid }, Width: 5, Numeric: func(p *Process) interface{} { return p.PidInt() }},
+	"ppid":  {Header: "PPID", Extract: func(p *Process) string { return p.Ppid }, Width: 5, Numeric: func(p *Process) interface{} { return p.PpidInt() }},
+	"pgrp":  {Header: "PGRP", Extract: func(p *Process) string { return p.Pgrp }, Width: 5},
+	"sid":   {Header: "SID", Extract: func(p *Process) string { return p.Sid }, Width: 5},
+	"pcpu":  {Header: "%CPU", Extract: func(p *Process) string { return pcpuColumn(p) }, Width: 4, Numeric: func(p *Process) interface{} { return p.CPUPercent() }},
+	"pmem":  {Header: "%MEM", Extract: func(p *Process) string { return memPercent(*p) }, Width: 4, Numeric: func(p *Process) interface{} { return p.MemPercent() }},
+	"rss":   {Header: "RSS", Extract: func(p *Process) string { return rssKiB(p) }, Width: 8, Numeric: func(p *Process) interface{} { return p.RssKB() }},
+	"vsz":   {Header: "VSZ", Extract: func(p *Process) string { return vszKiB(p) }, Width: 8, Numeric: func(p *Process) interface{} { return p.VszKB() }},
+	"utime": {Header: "UTIME", Extract: func(p *Process) string { return p.Utime }, Width: 8, Numeric: func(p *Process) interface{} { return p.UtimeInt() }},
+	"stime": {Header: "STIME", Extract: func(p *Process) string { return p.Stime }, Width: 8, Numeric: func(p *Process) interface{} { return p.StimeInt() }},
+	"tty":   {Header: "TTY", Extract: func(p *Process) string { return p.Ctty }, Width: 8},
+	"stat":  {Header: "STAT", Extract: func(p *Process) string { return p.State }, Width: 4},
+	"state": {Header: "S", Extract: func(p *Process) string { return p.State }, Width: 1},
+	"start": {Header: "START", Extract: func(p *Process) string { return p.StartTime }, Width: 8},
+	"time":  {Header: "TIME", Extract: func(p *Process) string { return cpuTime(*p) }, Width: 8},
+	"user":  {Header: "USER", Extract: func(p *Process) string { return userName(p.Uid) }, Width: 8},
+	"group": {Header: "GROUP", Extract: func(p *Process) string { return p.Group() }, Width: 8},
+	"comm":  {Header: "COMMAND", Extract: func(p *Process) string { return p.Cmd }, Width: 16},
+	"args":  {Header: "COMMAND", Extract: func(p *Process) string { return p.commandLine() }, Width: 0},
+	"nice":  {Header: "NI", Extract: func(p *Process) string { return p.Nice() }, Width: 3, Numeric: func(p *Process) interface{} { return p.NiceInt() }},
+	"pri":   {Header: "PRI", Extract: func(p *Process) string { return p.Priority() }, Width: 3, Numeric: func(p *Process) interface{} { return p.PriInt() }},
+	"wchan": {Header: "WCHAN", Extract: func(p *Process) string { return p.Wchan() }, Width: 8},
+
+	"capeff":    {Header: "CAPEFF", Extract: func(p *Process) string { return p.CapEff() }, Width: 16},
+	"seccomp":   {Header: "SECCOMP", Extract: func(p *Process) string { return p.Seccomp() }, Width: 7},
+	"cgroup":    {Header: "CGROUP", Extract: func(p *Process) string { return p.Cgroup() }, Width: 16},
+	"rbytes":    {Header: "RBYTES", Extract: func(p *Process) string { return p.RBytes() }, Width: 10},
+	"wbytes":    {Header: "WBYTES", Extract: func(p *Process) string { return p.WBytes() }, Width: 10},
+	"oom":       {Header: "OOM", Extract: func(p *Process) string { return p.OomScore() }, Width: 3},
+	"loginuser": {Header: "LOGINUSER", Extract: func(p *Process) string { return p.LoginUser() }, Width: 9},
+	"label":     {Header: "LABEL", Extract: func(p *Process) string { return p.Label() }, Width: 32},
+}
+
+func init() {
+	for name, d := range descriptors {
+		d.Key = name
+		descriptors[name] = d
+	}
+}
+
+// formatAliases maps the spellings users actually type on the command line
+// (borrowed from POSIX ps, e.g. %cpu) to a descriptors key.
+var formatAliases = map[string]string{
+	"%cpu": "pcpu",
+	"%mem": "pmem",
+	"cmd":  "args",
+}
+
+// defaultColumns is the column set ps prints when -o/-O aren't given,
+// matching the classic ps default of PID, TTY, TIME, CMD.
+var defaultColumns = []string{"pid", "tty", "time", "args"}
+
+func (p *Process) commandLine() string {
+	if p.Args != "" {
+		return p.Args
+	}
+	return p.Cmd
+}
+
+// parseFormat turns a -o style spec ("pid,user,%cpu,rss,comm" or
+// "pid,comm=COMMAND") into the Descriptors to print, in order.
+func parseFormat(spec string) ([]Descriptor, error) {
+	var descs []Descriptor
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		name, header := field, ""
+		if i := strings.IndexByte(field, '='); i >= 0 {
+			name, header = field[:i], field[i+1:]
+		}
+
+		name = strings.ToLower(name)
+		if alias, ok := formatAliases[name]; ok {
+			name = alias
+		}
+
+		d, ok := descriptors[name]
+		if !ok {
+			return nil, fmt.Errorf("ps: unknown -o field %q", name)
+		}
+		if header != "" {
+			d.Header = header
+		}
+		descs = append(descs, d)
+	}
+	return descs, nil
+}
+
+// defaultDescriptors returns the Descriptors for defaultColumns.
+func defaultDescriptors() []Descriptor {
+	descs, err := parseFormat(strings.Join(defaultColumns, ","))
+	if err != nil {
+		// defaultColumns is fixed at compile time and always valid.
+		panic(err)
+	}
+	return descs
+}
+
+// printTable renders pT using descs, sizing each column from
+// Descriptor.Width (falling back to the header length) and trimming the
+// last column to pT.maxwidth.
+func printTable(pT *ProcessTable, descs []Descriptor) {
+	headers := make([]string, len(descs))
+	for i, d := range descs {
+		headers[i] = fmt.Sprintf("%-*s", colWidth(d), d.Header)
+	}
+	fmt.Println(strings.TrimRight(strings.Join(headers, " "), " "))
+
+	for _, p := range pT.table {
+		p := p
+		cols := make([]string, len(descs))
+		for i, d := range descs {
+			cols[i] = fmt.Sprintf("%-*s", colWidth(d), d.Extract(&p))
+		}
+		line := strings.TrimRight(strings.Join(cols, " "), " ")
+		fmt.Println(truncateToWidth(line, pT.maxwidth))
+	}
+}
+
+func colWidth(d Descriptor) int {
+	if d.Width > len(d.Header) {
+		return d.Width
+	}
+	return len(d.Header)
+}
+
+// truncateToWidth cuts row down to maxwidth display columns (0 means
+// unbounded, i.e. don't truncate). It counts and slices by rune, not byte,
+// so a multibyte comm/args value - or printNode's multibyte box-drawing
+// connectors - isn't cut mid-rune into invalid UTF-8.
+func truncateToWidth(row string, maxwidth int) string {
+	if maxwidth <= 0 {
+		return row
+	}
+	runes := []rune(row)
+	if len(runes) <= maxwidth {
+		return row
+	}
+	return string(runes[:maxwidth])
+}
+
+// pcpuColumn prefers the delta %CPU a ProcessTable.Refresh computed (it's
+// far more representative of current load); it falls back to the
+// lifetime-average cpuPercent when there's no prior sample to diff
+// against, i.e. outside of -watch.
+func pcpuColumn(p *Process) string {
+	if p.deltaPcpu != "" {
+		return p.deltaPcpu
+	}
+	return cpuPercent(*p)
+}
+
+// cpuPercent computes %CPU the way classic ps does: (utime+stime) over the
+// wall-clock time the process has existed for.
+func cpuPercent(p Process) string {
+	ut, uerr := strconv.ParseFloat(p.Utime, 64)
+	st, serr := strconv.ParseFloat(p.Stime, 64)
+	start, sterr := strconv.ParseFloat(p.StartTime, 64)
+	if uerr != nil || serr != nil || sterr != nil {
+		return "?"
+	}
+
+	uptime, err := systemUptime()
+	if err != nil {
+		return "?"
+	}
+
+	elapsed := uptime - start/userHZ
+	if elapsed <= 0 {
+		return "0.0"
+	}
+
+	pct := (ut + st) / userHZ / elapsed * 100
+	return fmt.Sprintf("%.1f", pct)
+}
+
+// memPercent computes %MEM as rss (pages) converted to bytes over total
+// system memory.
+func memPercent(p Process) string {
+	rss, err := strconv.ParseFloat(p.Rss, 64)
+	if err != nil {
+		return "?"
+	}
+
+	total, err := memTotalKB()
+	if err != nil || total == 0 {
+		return "?"
+	}
+
+	rssKB := rss * float64(pageSizeBytes()) / 1024
+	return fmt.Sprintf("%.1f", rssKB/float64(total)*100)
+}
+
+// rssKiB formats rss (a page count, straight from /proc/<pid>/stat) as
+// KiB, the unit ps/top report RSS in.
+func rssKiB(p *Process) string {
+	pages, err := strconv.ParseUint(p.Rss, 10, 64)
+	if err != nil {
+		return "?"
+	}
+	return strconv.FormatUint(pages*uint64(pageSizeBytes())/1024, 10)
+}
+
+// vszKiB formats vsz (bytes, straight from /proc/<pid>/stat) as KiB, the
+// unit ps/top report VSZ in.
+func vszKiB(p *Process) string {
+	b, err := strconv.ParseUint(p.Vsize, 10, 64)
+	if err != nil {
+		return "?"
+	}
+	return strconv.FormatUint(b/1024, 10)
+}
+
+// cpuTime formats the total (user+kernel) CPU time as hh:mm:ss.
+func cpuTime(p Process) string {
+	ut, uerr := strconv.Atoi(p.Utime)
+	st, serr := strconv.Atoi(p.Stime)
+	if uerr != nil || serr != nil {
+		return "00:00:00"
+	}
+
+	jiffies := ut + st
+	tsecs := jiffies / userHZ
+	return fmt.Sprintf("%02d:%02d:%02d", tsecs/3600, (tsecs/60)%60, tsecs%60)
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		spec    string
+		want    []string // expected Descriptor.Key in order
+		wantErr bool
+	}{
+		{name: "single field", spec: "pid", want: []string{"pid"}},
+		{name: "multiple fields", spec: "pid,user,rss,comm", want: []string{"pid", "user", "rss", "comm"}},
+		{name: "whitespace around fields", spec: " pid , user ", want: []string{"pid", "user"}},
+		{name: "blank fields are skipped", spec: "pid,,user", want: []string{"pid", "user"}},
+		{name: "case insensitive", spec: "PID,User", want: []string{"pid", "user"}},
+		{name: "percent cpu alias", spec: "%cpu", want: []string{"pcpu"}},
+		{name: "percent mem alias", spec: "%mem", want: []string{"pmem"}},
+		{name: "cmd alias", spec: "cmd", want: []string{"args"}},
+		{name: "label field is registered", spec: "pid,label", want: []string{"pid", "label"}},
+		{name: "unknown field errors", spec: "bogus", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			descs, err := parseFormat(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFormat(%q) = nil error, want one", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFormat(%q) = %v, want no error", tt.spec, err)
+			}
+
+			if len(descs) != len(tt.want) {
+				t.Fatalf("parseFormat(%q) = %d descriptors, want %d", tt.spec, len(descs), len(tt.want))
+			}
+			for i, key := range tt.want {
+				if descs[i].Key != key {
+					t.Errorf("parseFormat(%q)[%d].Key = %q, want %q", tt.spec, i, descs[i].Key, key)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormatCustomHeader(t *testing.T) {
+	descs, err := parseFormat("pid,comm=COMMAND")
+	if err != nil {
+		t.Fatalf("parseFormat() = %v, want no error", err)
+	}
+	if len(descs) != 2 {
+		t.Fatalf("parseFormat() = %d descriptors, want 2", len(descs))
+	}
+	if descs[1].Header != "COMMAND" {
+		t.Errorf("descs[1].Header = %q, want %q", descs[1].Header, "COMMAND")
+	}
+	if descs[1].Key != "comm" {
+		t.Errorf("descs[1].Key = %q, want %q", descs[1].Key, "comm")
+	}
+}
+
+func TestDefaultDescriptors(t *testing.T) {
+	descs := defaultDescriptors()
+	if len(descs) != len(defaultColumns) {
+		t.Fatalf("defaultDescriptors() = %d descriptors, want %d", len(descs), len(defaultColumns))
+	}
+	for i, col := range defaultColumns {
+		if descs[i].Key != col {
+			t.Errorf("defaultDescriptors()[%d].Key = %q, want %q", i, descs[i].Key, col)
+		}
+	}
+}
+
+func TestRssKiB(t *testing.T) {
+	p := &Process{ProcessInfo: ProcessInfo{Rss: "10"}}
+	want := strconv.FormatUint(10*uint64(pageSizeBytes())/1024, 10)
+	if got := rssKiB(p); got != want {
+		t.Errorf("rssKiB() = %q, want %q", got, want)
+	}
+}
+
+func TestRssKiBInvalid(t *testing.T) {
+	p := &Process{ProcessInfo: ProcessInfo{Rss: "not-a-number"}}
+	if got := rssKiB(p); got != "?" {
+		t.Errorf("rssKiB() = %q, want %q", got, "?")
+	}
+}
+
+func TestVszKiB(t *testing.T) {
+	p := &Process{ProcessInfo: ProcessInfo{Vsize: "2048"}}
+	if got := vszKiB(p); got != "2" {
+		t.Errorf("vszKiB() = %q, want %q", got, "2")
+	}
+}
+
+func TestVszKiBInvalid(t *testing.T) {
+	p := &Process{ProcessInfo: ProcessInfo{Vsize: "nope"}}
+	if got := vszKiB(p); got != "?" {
+		t.Errorf("vszKiB() = %q, want %q", got, "?")
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		row      string
+		maxwidth int
+		want     string
+	}{
+		{name: "zero means unbounded", row: "hello world", maxwidth: 0, want: "hello world"},
+		{name: "shorter than maxwidth is unchanged", row: "hi", maxwidth: 10, want: "hi"},
+		{name: "ascii is cut at maxwidth", row: "hello world", maxwidth: 5, want: "hello"},
+		{
+			// Each box-drawing connector (├─, └─, │) and each CJK comm
+			// character is a multibyte rune; truncating by byte index
+			// would cut mid-rune long before maxwidth display columns.
+			name:     "multibyte runes are cut by rune count, not byte count",
+			row:      "├─ 日本語(123)",
+			maxwidth: 5,
+			want:     "├─ 日本",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateToWidth(tt.row, tt.maxwidth); got != tt.want {
+				t.Errorf("truncateToWidth(%q, %d) = %q, want %q", tt.row, tt.maxwidth, got, tt.want)
+			}
+		})
+	}
+}
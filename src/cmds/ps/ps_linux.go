@@ -9,9 +9,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/user"
 	"path"
 	"path/filepath"
-	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -22,25 +22,15 @@ import (
 const (
 	allProc = "^[0-9]+$"
 	proc    = "/proc"
-	USER_HZ = 100
 )
 
-// Portable way to implement ps cross-plataform
-// Like the os.File
-type Process struct {
-	process
-}
-
+// linuxExtra holds the /proc/<pid>/stat fields that don't have an
+// OS-independent home in ProcessInfo.
+//
 // table content of stat file defined by:
 // https://www.kernel.org/doc/Documentation/filesystems/proc.txt (2009)
 // Section (ctrl + f) : Table 1-4: Contents of the stat files (as of 2.6.30-rc7)
-type process struct {
-	Pid         string // process id
-	Cmd         string // filename of the executable
-	State       string // state (R is running, S is sleeping, D is sleeping in an uninterruptible wait, Z is zombie, T is traced or stopped)
-	Ppid        string // process id of the parent process
-	Pgrp        string // pgrp of the process
-	Sid         string // session id
+type linuxExtra struct {
 	TTYNr       string // tty the process uses
 	TTYPgrp     string // pgrp of the tty
 	Flags       string // task flags
@@ -48,17 +38,12 @@ type process struct {
 	CminFlt     string // number of minor faults with child's
 	MajFlt      string // number of major faults
 	CmajFlt     string // number of major faults with child's
-	Utime       string // user mode jiffies
-	Stime       string // kernel mode jiffies
 	Cutime      string // user mode jiffies with child's
 	Cstime      string // kernel mode jiffies with child's
 	Priority    string // priority level
 	Nice        string // nice level
 	NumThreads  string // number of threads
 	ItRealValue string // (obsolete, always 0)
-	StartTime   string // time the process started after system boot
-	Vsize       string // virtual memory size
-	Rss         string // resident set memory size
 	Rsslim      string // current limit in bytes on the rss
 	StartCode   string // address above which program text can run
 	EndCode     string // address below which program text can run
@@ -87,109 +72,491 @@ type process struct {
 	EnvStart    string // address above which program environment is placed
 	EnvEnd      string // address below which program environment is placed
 	ExitCode    string // the thread's exit_code in the form reported by the waitpid system call (end of stat)
-	Ctty        string // extra member (don't parsed from stat)
-	Time        string // extra member (don't parsed from stat)
+	Time        string // extra member (not parsed from stat), formatted total CPU time
+
+	// The fields below aren't columns of /proc/<pid>/stat at all - they're
+	// filled in by readStatusExtra and the other /proc/<pid>/* readers
+	// further down this file, and exist here purely so the capeff,
+	// cgroup, rbytes, ... descriptors have somewhere to read from.
+	Gid          string // primary group id, from /proc/<pid>/status
+	Groups       string // supplementary group ids, comma-separated
+	CapEff       string // effective capabilities, raw hex bitmask
+	CapPrm       string // permitted capabilities, raw hex bitmask
+	CapBnd       string // bounding set capabilities, raw hex bitmask
+	Seccomp      string // seccomp mode: 0 disabled, 1 strict, 2 filter
+	NSpid        string // pid as seen in each pid namespace it's nested in, innermost last
+	Cgroup       string // cgroup membership, from /proc/<pid>/cgroup
+	OomScore     string // oom killer badness score, from /proc/<pid>/oom_score
+	LoginUid     string // the uid that originally logged in, from /proc/<pid>/loginuid
+	IORchar      string // bytes read, including from the page cache
+	IOWchar      string // bytes written, including to the page cache
+	IOReadBytes  string // bytes actually fetched from storage
+	IOWriteBytes string // bytes actually sent to storage
+	Label        string // LSM security label (SELinux/AppArmor), from /proc/<pid>/attr/current
 }
 
-// Parse all content of stat to a Process Struct
-// by gived the pid (linux)
-func (p *process) readStat(pid string) error {
-	b, err := ioutil.ReadFile(path.Join(proc, pid, "stat"))
+// statFields lists the /proc/<pid>/stat columns in on-disk order, so
+// readStat can fan each one out to either ProcessInfo or linuxExtra.
+var statFields = []string{
+	"Pid", "Cmd", "State", "Ppid", "Pgrp", "Sid", "TTYNr", "TTYPgrp",
+	"Flags", "MinFlt", "CminFlt", "MajFlt", "CmajFlt", "Utime", "Stime",
+	"Cutime", "Cstime", "Priority", "Nice", "NumThreads", "ItRealValue",
+	"StartTime", "Vsize", "Rss", "Rsslim", "StartCode", "EndCode",
+	"StartStack", "Esp", "Eip", "Pending", "Blocked", "Sigign", "Sigcatch",
+	"Wchan", "Zero1", "Zero2", "ExitSignal", "TaskCpu", "RtPriority",
+	"Policy", "BlkioTicks", "Gtime", "Cgtime", "StartData", "EndData",
+	"StartBrk", "ArgStart", "ArgEnd", "EnvStart", "EnvEnd", "ExitCode",
+}
+
+// procSource reads process information from Linux's /proc filesystem.
+type procSource struct{}
+
+// newProcessSource returns the ProcessSource for the current OS.
+func newProcessSource() ProcessSource {
+	return procSource{}
+}
+
+// List walks /proc and parses every numeric entry into a Process.
+func (s procSource) List() ([]Process, error) {
+	var table []Process
+
+	pf := regexp.MustCompile(allProc)
+	err := filepath.Walk(proc, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// A directory we were about to descend into (most often
+			// /proc/<pid> itself) can disappear between readdir and
+			// stat if the process exits mid-scan. That's normal, not
+			// a reason to abort the whole table.
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			log.Printf("%v: %v\n", name, err)
+			return filepath.SkipDir
+		}
+		if name == proc {
+			return nil
+		}
+
+		if pf.Match([]byte(fi.Name())) {
+			p, err := s.Read(fi.Name())
+			if err != nil {
+				if !os.IsNotExist(err) {
+					log.Print(err)
+				}
+				return filepath.SkipDir
+			}
+			table = append(table, p)
+		}
+
+		return filepath.SkipDir
+	})
+
+	return table, err
+}
 
+// Read parses /proc/<pid>/stat into a Process.
+func (procSource) Read(pid string) (Process, error) {
+	var p Process
+	extra := &linuxExtra{}
+
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "stat"))
 	if err != nil {
-		return err
+		return p, err
 	}
 
 	fields := strings.Split(string(b), " ")
+	info := &p.ProcessInfo
+	for i := 0; i < len(fields) && i < len(statFields); i++ {
+		setStatField(info, extra, statFields[i], fields[i])
+	}
+
+	extra.Time = getTime(info.Utime, info.Stime)
+	info.Ctty = getCtty(info.Pid, extra.TTYPgrp)
+	cmd := info.Cmd
+	info.Cmd = cmd[1 : len(cmd)-1]
+
+	if cmdline, err := longCmdLine(info.Pid); err == nil {
+		info.Args = strings.Replace(cmdline, "\x00", " ", -1)
+		info.Args = strings.TrimSpace(info.Args)
+	}
+	if flags.x && info.Args != "" {
+		info.Cmd = info.Args
+	}
+
+	se := readStatusExtra(info.Pid)
+	info.Uid = se.Uid
+	extra.Gid = se.Gid
+	extra.Groups = se.Groups
+	extra.CapEff = se.CapEff
+	extra.CapPrm = se.CapPrm
+	extra.CapBnd = se.CapBnd
+	extra.Seccomp = se.Seccomp
+	extra.NSpid = se.NSpid
+	extra.Cgroup = readCgroup(info.Pid)
+	extra.OomScore = readOomScore(info.Pid)
+	extra.LoginUid = readLoginUid(info.Pid)
+	extra.Label = readLabel(info.Pid)
+	io := readIO(info.Pid)
+	extra.IORchar = io.Rchar
+	extra.IOWchar = io.Wchar
+	extra.IOReadBytes = io.ReadBytes
+	extra.IOWriteBytes = io.WriteBytes
+
+	p.extra = extra
+	return p, nil
+}
 
-	// set struct fields from stat file data
-	v := reflect.ValueOf(p).Elem()
-	for i := 0; i < len(fields); i++ {
-		fieldVal := v.Field(i)
-		fieldVal.Set(reflect.ValueOf(fields[i]))
+// Namespaces returns the namespace ids pid belongs to, read from the
+// symlinks under /proc/<pid>/ns.
+func (procSource) Namespaces(pid string) (map[string]string, error) {
+	nsDir := path.Join(proc, pid, "ns")
+	entries, err := ioutil.ReadDir(nsDir)
+	if err != nil {
+		return nil, err
 	}
 
-	p.Time = p.getTime()
-	p.Ctty = p.getCtty()
-	cmd := p.Cmd
-	p.Cmd = cmd[1 : len(cmd)-1]
-	if flags.x {
-		cmdline, err := p.longCmdLine()
+	ns := map[string]string{}
+	for _, e := range entries {
+		link, err := os.Readlink(path.Join(nsDir, e.Name()))
 		if err != nil {
-			return err
-		}
-		if cmdline != "" {
-			p.Cmd = cmdline
+			continue
 		}
+		ns[e.Name()] = link
 	}
+	return ns, nil
+}
 
-	return nil
+// setStatField assigns a single /proc/<pid>/stat column to either the
+// OS-independent ProcessInfo or the Linux-specific extras.
+func setStatField(info *ProcessInfo, extra *linuxExtra, name, value string) {
+	switch name {
+	case "Pid":
+		info.Pid = value
+	case "Cmd":
+		info.Cmd = value
+	case "State":
+		info.State = value
+	case "Ppid":
+		info.Ppid = value
+	case "Pgrp":
+		info.Pgrp = value
+	case "Sid":
+		info.Sid = value
+	case "Utime":
+		info.Utime = value
+	case "Stime":
+		info.Stime = value
+	case "StartTime":
+		info.StartTime = value
+	case "Vsize":
+		info.Vsize = value
+	case "Rss":
+		info.Rss = value
+	case "TTYNr":
+		extra.TTYNr = value
+	case "TTYPgrp":
+		extra.TTYPgrp = value
+	case "Flags":
+		extra.Flags = value
+	case "MinFlt":
+		extra.MinFlt = value
+	case "CminFlt":
+		extra.CminFlt = value
+	case "MajFlt":
+		extra.MajFlt = value
+	case "CmajFlt":
+		extra.CmajFlt = value
+	case "Cutime":
+		extra.Cutime = value
+	case "Cstime":
+		extra.Cstime = value
+	case "Priority":
+		extra.Priority = value
+	case "Nice":
+		extra.Nice = value
+	case "NumThreads":
+		extra.NumThreads = value
+	case "ItRealValue":
+		extra.ItRealValue = value
+	case "Rsslim":
+		extra.Rsslim = value
+	case "StartCode":
+		extra.StartCode = value
+	case "EndCode":
+		extra.EndCode = value
+	case "StartStack":
+		extra.StartStack = value
+	case "Esp":
+		extra.Esp = value
+	case "Eip":
+		extra.Eip = value
+	case "Pending":
+		extra.Pending = value
+	case "Blocked":
+		extra.Blocked = value
+	case "Sigign":
+		extra.Sigign = value
+	case "Sigcatch":
+		extra.Sigcatch = value
+	case "Wchan":
+		extra.Wchan = value
+	case "Zero1":
+		extra.Zero1 = value
+	case "Zero2":
+		extra.Zero2 = value
+	case "ExitSignal":
+		extra.ExitSignal = value
+	case "TaskCpu":
+		extra.TaskCpu = value
+	case "RtPriority":
+		extra.RtPriority = value
+	case "Policy":
+		extra.Policy = value
+	case "BlkioTicks":
+		extra.BlkioTicks = value
+	case "Gtime":
+		extra.Gtime = value
+	case "Cgtime":
+		extra.Cgtime = value
+	case "StartData":
+		extra.StartData = value
+	case "EndData":
+		extra.EndData = value
+	case "StartBrk":
+		extra.StartBrk = value
+	case "ArgStart":
+		extra.ArgStart = value
+	case "ArgEnd":
+		extra.ArgEnd = value
+	case "EnvStart":
+		extra.EnvStart = value
+	case "EnvEnd":
+		extra.EnvEnd = value
+	case "ExitCode":
+		extra.ExitCode = value
+	}
 }
 
-// Fetch data from Operating System about process
-// on Linux read data from stat
-func (p *Process) Parse(pid string) error {
-	if err := p.process.readStat(pid); err != nil {
-		return err
+// statusExtra holds the /proc/<pid>/status fields ps reads in one pass: the
+// owning uid, group ids, capability sets, seccomp mode and pid namespace
+// chain - the fields setStatField's /proc/<pid>/stat pass has no
+// equivalent for.
+type statusExtra struct {
+	Uid, Gid, Groups, CapEff, CapPrm, CapBnd, Seccomp, NSpid string
+}
+
+// readStatusExtra parses /proc/<pid>/status for the owning uid, group ids,
+// capability sets, the seccomp mode and the pid namespace chain.
+func readStatusExtra(pid string) statusExtra {
+	var se statusExtra
+
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "status"))
+	if err != nil {
+		return se
 	}
 
-	return nil
+	for _, line := range strings.Split(string(b), "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key, val := line[:i], strings.TrimSpace(line[i+1:])
 
+		switch key {
+		case "Uid":
+			if fields := strings.Fields(val); len(fields) > 0 {
+				se.Uid = fields[0]
+			}
+		case "Gid":
+			if fields := strings.Fields(val); len(fields) > 0 {
+				se.Gid = fields[0]
+			}
+		case "Groups":
+			se.Groups = strings.Join(strings.Fields(val), ",")
+		case "CapEff":
+			se.CapEff = val
+		case "CapPrm":
+			se.CapPrm = val
+		case "CapBnd":
+			se.CapBnd = val
+		case "Seccomp":
+			se.Seccomp = val
+		case "NSpid":
+			se.NSpid = strings.Join(strings.Fields(val), ",")
+		}
+	}
+	return se
 }
 
-// ctty returns the ctty or "?" if none can be found.
-// TODO: an right way to get ctty by p.TTYNr and p.TTYPgrp
-func (p process) getCtty() string {
-	if tty, err := os.Readlink(path.Join(proc, p.Pid, "fd/0")); err != nil {
-		return "?"
-	} else if p.TTYPgrp != "-1" {
-		if len(tty) > 5 && tty[:5] == "/dev/" {
-			tty = tty[5:]
+// readCgroup returns pid's cgroup membership, from /proc/<pid>/cgroup.
+func readCgroup(pid string) string {
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "cgroup"))
+	if err != nil {
+		return "-"
+	}
+	return parseCgroup(string(b))
+}
+
+// parseCgroup decodes the contents of /proc/<pid>/cgroup. On a cgroup v2
+// system that file is a single unified-hierarchy line and the path alone
+// is reported; on v1 it's one line per controller hierarchy, reported as
+// a controller-keyed map ("cpu=/,memory=/user.slice").
+func parseCgroup(data string) string {
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	if len(lines) == 1 {
+		if i := strings.Index(lines[0], "::"); i >= 0 {
+			return lines[0][i+2:]
 		}
-		return tty
+		return lines[0]
 	}
-	return "?"
+
+	var pairs []string
+	for _, line := range lines {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 || fields[1] == "" {
+			continue // v2-only entry, or a named (non-controller) hierarchy
+		}
+		pairs = append(pairs, fields[1]+"="+fields[2])
+	}
+	if len(pairs) == 0 {
+		return "-"
+	}
+	return strings.Join(pairs, ",")
 }
 
-// Get a named field of stat type
-// e.g.: p.getField("Pid") => '1'
-func (p *process) getField(field string) string {
-	v := reflect.ValueOf(p).Elem()
-	return fmt.Sprintf("%v", v.FieldByName(field))
+// readOomScore returns pid's oom_score, the kernel's badness rating for
+// which process the OOM killer will pick first.
+func readOomScore(pid string) string {
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "oom_score"))
+	if err != nil {
+		return "-"
+	}
+	return strings.TrimSpace(string(b))
 }
 
-// Search for attributes about the process
-//
-func (p Process) Search(field string) string {
-	return p.process.getField(field)
+// readLoginUid returns the uid that originally logged pid's session in,
+// from /proc/<pid>/loginuid. The kernel reports the sentinel value
+// 4294967295 (-1 as uint32) for processes with no audit login uid set,
+// which is most of them outside an SSH or console session; that's treated
+// the same as any other field ps can't fill in.
+func readLoginUid(pid string) string {
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "loginuid"))
+	if err != nil {
+		return "-"
+	}
+	if uid := strings.TrimSpace(string(b)); uid != "" && uid != "4294967295" {
+		return uid
+	}
+	return "-"
 }
 
-// read UID of process based on or
-func (p process) getUid() (int, error) {
-	b, err := ioutil.ReadFile(path.Join(proc, p.Pid, "status"))
+// readLabel returns pid's LSM security label (e.g. an SELinux context or an
+// AppArmor profile name), from /proc/<pid>/attr/current. A process with no
+// LSM enabled, or whose label a non-root ps can't read, reports "-" like
+// any other field ps can't fill in.
+func readLabel(pid string) string {
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "attr", "current"))
+	if err != nil {
+		return "-"
+	}
+	if label := strings.TrimSpace(string(b)); label != "" {
+		return label
+	}
+	return "-"
+}
 
-	var uid int
-	lines := strings.Split(string(b), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "Uid") {
-			fields := strings.Split(line, "\t")
-			uid, err = strconv.Atoi(fields[1])
-			break
-		}
+// ioCounters holds the fields of /proc/<pid>/io ps exposes.
+type ioCounters struct {
+	Rchar, Wchar, ReadBytes, WriteBytes string
+}
+
+// readIO parses /proc/<pid>/io. A process can't read another uid's io file,
+// so this is empty more often than the other /proc/<pid>/* readers; that's
+// reported the same way as any other unreadable field.
+func readIO(pid string) ioCounters {
+	var io ioCounters
+
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "io"))
+	if err != nil {
+		return io
 	}
 
-	return uid, err
+	for _, line := range strings.Split(string(b), "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key, val := line[:i], strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "rchar":
+			io.Rchar = val
+		case "wchar":
+			io.Wchar = val
+		case "read_bytes":
+			io.ReadBytes = val
+		case "write_bytes":
+			io.WriteBytes = val
+		}
+	}
+	return io
+}
 
+// capabilityNames lists the Linux capability bits in bit order (CAP_CHOWN
+// is bit 0, CAP_CHECKPOINT_RESTORE is bit 40), so a CapEff/CapPrm/CapBnd
+// bitmask can be decoded to the names capsh/getpcaps print instead of raw
+// hex. See include/uapi/linux/capability.h.
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap",
+	"cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
 }
 
-func (p Process) GetUid() (int, error) {
-	return p.process.getUid()
+// decodeCaps turns a raw hex capability bitmask (as stored in
+// linuxExtra.CapEff etc.) into its comma-separated capability names.
+func decodeCaps(hex string) string {
+	mask, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil || mask == 0 {
+		return "-"
+	}
+
+	var names []string
+	for bit, name := range capabilityNames {
+		if mask&(1<<uint(bit)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
 }
 
-// change p.Cmd to long command line with args
-func (p process) longCmdLine() (string, error) {
-	b, err := ioutil.ReadFile(path.Join(proc, p.Pid, "cmdline"))
+// getCtty returns the ctty or "?" if none can be found.
+// TODO: a right way to get ctty by TTYNr and ttyPgrp
+func getCtty(pid, ttyPgrp string) string {
+	if tty, err := os.Readlink(path.Join(proc, pid, "fd/0")); err != nil {
+		return "?"
+	} else if ttyPgrp != "-1" {
+		if len(tty) > 5 && tty[:5] == "/dev/" {
+			tty = tty[5:]
+		}
+		return tty
+	}
+	return "?"
+}
 
+// longCmdLine reads the full command line (with args) for pid.
+func longCmdLine(pid string) (string, error) {
+	b, err := ioutil.ReadFile(path.Join(proc, pid, "cmdline"))
 	if err != nil {
 		return "", err
 	}
@@ -197,13 +564,13 @@ func (p process) longCmdLine() (string, error) {
 	return string(b), nil
 }
 
-// Get total time stat formated hh:mm:ss
-func (p process) getTime() string {
-	utime, _ := strconv.Atoi(p.Utime)
-	stime, _ := strconv.Atoi(p.Stime)
+// getTime formats the total (user+kernel) CPU time as hh:mm:ss.
+func getTime(utimeStr, stimeStr string) string {
+	utime, _ := strconv.Atoi(utimeStr)
+	stime, _ := strconv.Atoi(stimeStr)
 	jiffies := utime + stime
 
-	tsecs := jiffies / USER_HZ
+	tsecs := jiffies / userHZ
 	secs := int(tsecs % 60)
 	mins := int((tsecs / 60) % 60)
 	hrs := tsecs / 3600
@@ -211,6 +578,157 @@ func (p process) getTime() string {
 	return fmt.Sprintf("%02d:%02d:%02d", hrs, mins, secs)
 }
 
+// Nice returns the process's nice value.
+func (p Process) Nice() string {
+	if e, ok := p.extra.(*linuxExtra); ok {
+		return e.Nice
+	}
+	return "-"
+}
+
+// Priority returns the process's scheduling priority.
+func (p Process) Priority() string {
+	if e, ok := p.extra.(*linuxExtra); ok {
+		return e.Priority
+	}
+	return "-"
+}
+
+// Wchan returns the kernel function the process is sleeping in, or "-" if
+// it isn't sleeping or the field isn't available.
+func (p Process) Wchan() string {
+	if e, ok := p.extra.(*linuxExtra); ok && e.Wchan != "0" {
+		return e.Wchan
+	}
+	return "-"
+}
+
+// Group returns the process's owning group name. Not yet implemented: ps
+// only parses /proc/<pid>/status for the owning uid today.
+func (p Process) Group() string {
+	return "-"
+}
+
+// CapEff returns the process's effective capability set, decoded from the
+// raw bitmask to human-readable names (e.g. "cap_net_admin,cap_sys_ptrace").
+func (p Process) CapEff() string {
+	if e, ok := p.extra.(*linuxExtra); ok {
+		return decodeCaps(e.CapEff)
+	}
+	return "-"
+}
+
+// Seccomp returns the process's seccomp mode (0 disabled, 1 strict, 2
+// filter), or "-" if it couldn't be read.
+func (p Process) Seccomp() string {
+	if e, ok := p.extra.(*linuxExtra); ok && e.Seccomp != "" {
+		return e.Seccomp
+	}
+	return "-"
+}
+
+// Cgroup returns the process's cgroup membership; see readCgroup.
+func (p Process) Cgroup() string {
+	if e, ok := p.extra.(*linuxExtra); ok {
+		return e.Cgroup
+	}
+	return "-"
+}
+
+// RBytes returns the bytes the process actually fetched from storage, from
+// /proc/<pid>/io.
+func (p Process) RBytes() string {
+	if e, ok := p.extra.(*linuxExtra); ok && e.IOReadBytes != "" {
+		return e.IOReadBytes
+	}
+	return "-"
+}
+
+// WBytes returns the bytes the process actually sent to storage, from
+// /proc/<pid>/io.
+func (p Process) WBytes() string {
+	if e, ok := p.extra.(*linuxExtra); ok && e.IOWriteBytes != "" {
+		return e.IOWriteBytes
+	}
+	return "-"
+}
+
+// OomScore returns the process's oom_score, the kernel's badness rating
+// for which process the OOM killer will pick first.
+func (p Process) OomScore() string {
+	if e, ok := p.extra.(*linuxExtra); ok {
+		return e.OomScore
+	}
+	return "-"
+}
+
+// LoginUser returns the username for the uid that originally logged the
+// process's session in, or "-" if it never went through a login.
+func (p Process) LoginUser() string {
+	e, ok := p.extra.(*linuxExtra)
+	if !ok || e.LoginUid == "-" {
+		return "-"
+	}
+	return userName(e.LoginUid)
+}
+
+// Label returns the process's LSM security label; see readLabel.
+func (p Process) Label() string {
+	if e, ok := p.extra.(*linuxExtra); ok {
+		return e.Label
+	}
+	return "-"
+}
+
+// userName resolves uid to a username, falling back to the numeric uid if
+// the lookup fails.
+func userName(uid string) string {
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return uid
+	}
+	return u.Username
+}
+
+// systemUptime returns how long the system has been up, in seconds.
+func systemUptime() (float64, error) {
+	b, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("/proc/uptime: unexpected format")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// memTotalKB returns total system memory, in KB, from /proc/meminfo.
+func memTotalKB() (uint64, error) {
+	b, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if !strings.HasPrefix(line, "MemTotal:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+		return strconv.ParseUint(fields[1], 10, 64)
+	}
+	return 0, fmt.Errorf("/proc/meminfo: MemTotal not found")
+}
+
+// pageSizeBytes returns the kernel's memory page size.
+func pageSizeBytes() int {
+	return syscall.Getpagesize()
+}
+
 type winsize struct {
 	Row    uint16
 	Col    uint16
@@ -218,51 +736,19 @@ type winsize struct {
 	Ypixel uint16
 }
 
+// terminalSize returns the terminal's row and column count, or 0, 0 if
+// stdin isn't a terminal (piped, redirected, cron, ...) - a case every
+// non-interactive invocation of ps, including -o json/csv, must survive
+// rather than fail. Callers treat a 0 column count as "don't truncate".
 func terminalSize() (int, int) {
 	ws := &winsize{}
-	retCode, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
+	retCode, _, _ := syscall.Syscall(syscall.SYS_IOCTL,
 		uintptr(syscall.Stdin),
 		uintptr(syscall.TIOCGWINSZ),
 		uintptr(unsafe.Pointer(ws)))
 
 	if int(retCode) == -1 {
-		panic(errno)
+		return 0, 0
 	}
 	return int(ws.Row), int(ws.Col)
 }
-
-// Walk from the proc files
-// and parsing them
-func (pT *ProcessTable) LoadTable() error {
-	pf := regexp.MustCompile(allProc)
-	err := filepath.Walk(proc, func(name string, fi os.FileInfo, err error) error {
-		if err != nil {
-			log.Printf("%v: %v\n", name, err)
-			return err
-		}
-		if name == proc {
-			return nil
-		}
-
-		if pf.Match([]byte(fi.Name())) {
-			p := &Process{}
-			if err := p.Parse(fi.Name()); err != nil {
-				log.Print(err)
-				return err
-			}
-			pT.table = append(pT.table, *p)
-		}
-
-		return filepath.SkipDir
-	})
-
-	// set terminal max on load table
-	_, columnSize := terminalSize()
-	pT.maxwidth = columnSize
-
-	if err.Error() == "skip this directory" {
-		return nil
-	}
-
-	return err
-}
\ No newline at end of file
@@ -0,0 +1,81 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestDecodeCaps(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		hex  string
+		want string
+	}{
+		{name: "zero mask", hex: "0000000000000000", want: "-"},
+		{name: "empty string", hex: "", want: "-"},
+		{name: "invalid hex", hex: "not-hex", want: "-"},
+		{name: "single bit, cap_chown", hex: "1", want: "cap_chown"},
+		{name: "single bit, cap_kill", hex: "20", want: "cap_kill"},
+		{name: "multiple bits", hex: "3", want: "cap_chown,cap_dac_override"},
+		{
+			name: "full legacy 32-bit mask",
+			hex:  "ffffffff",
+			want: "cap_chown,cap_dac_override,cap_dac_read_search,cap_fowner," +
+				"cap_fsetid,cap_kill,cap_setgid,cap_setuid,cap_setpcap," +
+				"cap_linux_immutable,cap_net_bind_service,cap_net_broadcast," +
+				"cap_net_admin,cap_net_raw,cap_ipc_lock,cap_ipc_owner," +
+				"cap_sys_module,cap_sys_rawio,cap_sys_chroot,cap_sys_ptrace," +
+				"cap_sys_pacct,cap_sys_admin,cap_sys_boot,cap_sys_nice," +
+				"cap_sys_resource,cap_sys_time,cap_sys_tty_config,cap_mknod," +
+				"cap_lease,cap_audit_write,cap_audit_control,cap_setfcap",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeCaps(tt.hex); got != tt.want {
+				t.Errorf("decodeCaps(%q) = %q, want %q", tt.hex, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCgroup(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "cgroup v2 unified",
+			data: "0::/user.slice/user-1000.slice/session-1.scope\n",
+			want: "/user.slice/user-1000.slice/session-1.scope",
+		},
+		{
+			name: "cgroup v1 multiple controllers",
+			data: "11:memory:/user.slice\n" +
+				"10:cpu,cpuacct:/\n" +
+				"1:name=systemd:/user.slice/session-1.scope\n",
+			want: "memory=/user.slice,cpu,cpuacct=/,name=systemd=/user.slice/session-1.scope",
+		},
+		{
+			// readCgroup (not parseCgroup) is what reports "-" when the
+			// file can't be read at all; an empty but present file has no
+			// hierarchy lines to drop, so it falls into the single-line
+			// branch with nothing to trim off.
+			name: "empty file",
+			data: "",
+			want: "",
+		},
+		{
+			name: "single line without :: is reported verbatim",
+			data: "0:freezer:/\n",
+			want: "0:freezer:/",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCgroup(tt.data); got != tt.want {
+				t.Errorf("parseCgroup(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
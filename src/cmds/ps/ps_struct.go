@@ -0,0 +1,72 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// isStructuredFormat reports whether format names a structured output mode
+// (-o json or -o csv) rather than a POSIX column list.
+func isStructuredFormat(format string) bool {
+	return format == "json" || format == "csv"
+}
+
+// writeJSON serializes table as a JSON array, one object per process, using
+// descs for both the set of fields and their order. Descriptors with a
+// Numeric accessor (pid, rss, pcpu, ...) are emitted as JSON numbers;
+// everything else is emitted as a string, the same value printTable would
+// print.
+func writeJSON(w io.Writer, table []Process, descs []Descriptor) error {
+	rows := make([]map[string]interface{}, len(table))
+	for i := range table {
+		p := &table[i]
+		row := make(map[string]interface{}, len(descs))
+		for _, d := range descs {
+			if d.Numeric != nil {
+				row[d.Key] = d.Numeric(p)
+			} else {
+				row[d.Key] = d.Extract(p)
+			}
+		}
+		rows[i] = row
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+// writeCSV serializes table as CSV, with a header row of descs' keys
+// followed by one row per process. Every column is written as a string
+// (CSV has no native number type), via the same Extract POSIX -o output
+// uses.
+func writeCSV(w io.Writer, table []Process, descs []Descriptor) error {
+	cw := csv.NewWriter(w)
+
+	header := make([]string, len(descs))
+	for i, d := range descs {
+		header[i] = d.Key
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i := range table {
+		p := &table[i]
+		row := make([]string, len(descs))
+		for j, d := range descs {
+			row[j] = d.Extract(p)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
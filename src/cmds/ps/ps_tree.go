@@ -0,0 +1,135 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// treeNode is one process in the forest built by buildForest, along with
+// its children in the sibling order sortForest left it in.
+type treeNode struct {
+	proc     *Process
+	children []*treeNode
+}
+
+// buildForest turns a flat ProcessTable into one or more trees rooted at
+// root (pid 1 by default, or whatever --pid named), using each Process's
+// Ppid to find its parent. Subtrees whose Ppid isn't anywhere in the
+// table (the parent has already exited, or the table was filtered) are
+// gathered under a synthetic "?" root rather than dropped.
+func buildForest(table []Process, root, sortBy string) []*treeNode {
+	byPid := make(map[string]*Process, len(table))
+	for i := range table {
+		byPid[table[i].Pid] = &table[i]
+	}
+
+	children := map[string][]*Process{}
+	var orphans []*Process
+	for i := range table {
+		p := &table[i]
+		if p.Pid == root {
+			continue
+		}
+		if _, ok := byPid[p.Ppid]; ok {
+			children[p.Ppid] = append(children[p.Ppid], p)
+		} else {
+			orphans = append(orphans, p)
+		}
+	}
+
+	var build func(p *Process) *treeNode
+	build = func(p *Process) *treeNode {
+		kids := children[p.Pid]
+		sortSiblings(kids, sortBy)
+
+		n := &treeNode{proc: p}
+		for _, c := range kids {
+			n.children = append(n.children, build(c))
+		}
+		return n
+	}
+
+	var forest []*treeNode
+	if rp, ok := byPid[root]; ok {
+		forest = append(forest, build(rp))
+	}
+
+	if len(orphans) > 0 {
+		sortSiblings(orphans, sortBy)
+		synthetic := &treeNode{
+			proc: &Process{ProcessInfo: ProcessInfo{Pid: "?", Cmd: "(orphaned)"}},
+		}
+		for _, o := range orphans {
+			synthetic.children = append(synthetic.children, build(o))
+		}
+		forest = append(forest, synthetic)
+	}
+
+	return forest
+}
+
+// sortSiblings orders a process's children for display: "pid" (the
+// default), "start" (oldest first) or "cpu" (busiest first).
+func sortSiblings(procs []*Process, by string) {
+	sort.SliceStable(procs, func(i, j int) bool {
+		switch by {
+		case "start":
+			return atoi(procs[i].StartTime) < atoi(procs[j].StartTime)
+		case "cpu":
+			return cpuJiffies(procs[i]) > cpuJiffies(procs[j])
+		default:
+			return atoi(procs[i].Pid) < atoi(procs[j].Pid)
+		}
+	})
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func cpuJiffies(p *Process) int {
+	return atoi(p.Utime) + atoi(p.Stime)
+}
+
+// printForest renders a forest built by buildForest using the
+// ├─ / └─ / │ connectors pstree-style tools use. maxwidth (0 for
+// unbounded) caps each line's length, counting the tree prefix against
+// the same budget as the command text so long command lines don't push
+// the connectors off-screen.
+func printForest(forest []*treeNode, maxwidth int) {
+	for i, root := range forest {
+		printNode(root, "", true, i == len(forest)-1, maxwidth)
+	}
+}
+
+func printNode(n *treeNode, prefix string, isRoot, isLast bool, maxwidth int) {
+	line := prefix
+	if !isRoot {
+		if isLast {
+			line += "└─ "
+		} else {
+			line += "├─ "
+		}
+	}
+
+	label := fmt.Sprintf("%s(%s)", n.proc.commandLine(), n.proc.Pid)
+	fmt.Println(truncateToWidth(line+label, maxwidth))
+
+	childPrefix := prefix
+	if !isRoot {
+		if isLast {
+			childPrefix += "   "
+		} else {
+			childPrefix += "│  "
+		}
+	}
+	for i, c := range n.children {
+		printNode(c, childPrefix, false, i == len(n.children)-1, maxwidth)
+	}
+}
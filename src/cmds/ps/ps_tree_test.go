@@ -0,0 +1,136 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func newTestProc(pid, ppid string) Process {
+	return Process{ProcessInfo: ProcessInfo{Pid: pid, Ppid: ppid, Cmd: "p" + pid}}
+}
+
+// pids returns the root-to-leaf pid of every node in forest, in the order
+// buildForest/printForest would walk them, so a test can assert shape and
+// order with one comparison.
+func pids(forest []*treeNode) []string {
+	var out []string
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		out = append(out, n.proc.Pid)
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	for _, root := range forest {
+		walk(root)
+	}
+	return out
+}
+
+func TestBuildForestSimpleTree(t *testing.T) {
+	table := []Process{
+		newTestProc("1", "0"),
+		newTestProc("2", "1"),
+		newTestProc("3", "1"),
+		newTestProc("4", "2"),
+	}
+
+	forest := buildForest(table, "1", "pid")
+	if got, want := pids(forest), []string{"1", "2", "4", "3"}; !equalStrings(got, want) {
+		t.Errorf("pids(buildForest(...)) = %v, want %v", got, want)
+	}
+}
+
+func TestBuildForestOrphansGoUnderSyntheticRoot(t *testing.T) {
+	table := []Process{
+		newTestProc("1", "0"),
+		newTestProc("2", "1"),
+		newTestProc("50", "49"), // parent 49 isn't in the table
+	}
+
+	forest := buildForest(table, "1", "pid")
+	if len(forest) != 2 {
+		t.Fatalf("buildForest(...) = %d trees, want 2 (main root + orphans)", len(forest))
+	}
+
+	orphanRoot := forest[1]
+	if orphanRoot.proc.Pid != "?" {
+		t.Fatalf("orphanRoot.proc.Pid = %q, want %q", orphanRoot.proc.Pid, "?")
+	}
+	if len(orphanRoot.children) != 1 || orphanRoot.children[0].proc.Pid != "50" {
+		t.Fatalf("orphanRoot.children = %+v, want a single child with Pid 50", orphanRoot.children)
+	}
+}
+
+func TestBuildForestMissingRootYieldsOnlyOrphans(t *testing.T) {
+	table := []Process{
+		newTestProc("50", "49"),
+		newTestProc("51", "49"),
+	}
+
+	forest := buildForest(table, "1", "pid")
+	if len(forest) != 1 {
+		t.Fatalf("buildForest(...) = %d trees, want 1 (orphans only, no root 1 in table)", len(forest))
+	}
+	if forest[0].proc.Pid != "?" {
+		t.Fatalf("forest[0].proc.Pid = %q, want %q", forest[0].proc.Pid, "?")
+	}
+}
+
+func TestSortSiblingsByPid(t *testing.T) {
+	procs := []*Process{ptr(newTestProc("30", "1")), ptr(newTestProc("10", "1")), ptr(newTestProc("20", "1"))}
+	sortSiblings(procs, "pid")
+
+	want := []string{"10", "20", "30"}
+	for i, p := range procs {
+		if p.Pid != want[i] {
+			t.Errorf("procs[%d].Pid = %q, want %q", i, p.Pid, want[i])
+		}
+	}
+}
+
+func TestSortSiblingsByStart(t *testing.T) {
+	a, b, c := newTestProc("1", "0"), newTestProc("2", "0"), newTestProc("3", "0")
+	a.StartTime, b.StartTime, c.StartTime = "300", "100", "200"
+
+	procs := []*Process{&a, &b, &c}
+	sortSiblings(procs, "start")
+
+	want := []string{"2", "3", "1"} // oldest (lowest StartTime) first
+	for i, p := range procs {
+		if p.Pid != want[i] {
+			t.Errorf("procs[%d].Pid = %q, want %q", i, p.Pid, want[i])
+		}
+	}
+}
+
+func TestSortSiblingsByCPU(t *testing.T) {
+	a, b := newTestProc("1", "0"), newTestProc("2", "0")
+	a.Utime, a.Stime = "10", "0"
+	b.Utime, b.Stime = "100", "50"
+
+	procs := []*Process{&a, &b}
+	sortSiblings(procs, "cpu")
+
+	want := []string{"2", "1"} // busiest first
+	for i, p := range procs {
+		if p.Pid != want[i] {
+			t.Errorf("procs[%d].Pid = %q, want %q", i, p.Pid, want[i])
+		}
+	}
+}
+
+func ptr(p Process) *Process { return &p }
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
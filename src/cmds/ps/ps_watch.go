@@ -0,0 +1,80 @@
+// Copyright 2016 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+)
+
+// enterAltScreen and exitAltScreen switch the terminal to/from its
+// alternate screen buffer, the same trick top and vi use so the repaints
+// below don't spam the user's scrollback.
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	clearScreen    = "\x1b[2J\x1b[H"
+)
+
+// watch turns a one-shot ps invocation into a top(1)-style loop: it
+// repaints the process table every interval, computing %CPU as a delta
+// against the previous sample (ProcessTable.Refresh) rather than the
+// lifetime average a single LoadTable gives you.
+func watch(pT *ProcessTable, descs []Descriptor, interval time.Duration) {
+	fmt.Print(enterAltScreen)
+	defer fmt.Print(exitAltScreen)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		<-sigCh
+		fmt.Print(exitAltScreen)
+		os.Exit(0)
+	}()
+
+	for {
+		if err := pT.Refresh(); err != nil {
+			fmt.Print(exitAltScreen)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		sortByPcpu(pT.table)
+
+		fmt.Print(clearScreen)
+		if flags.forest {
+			root := flags.pid
+			if root == "" {
+				root = "1"
+			}
+			printForest(buildForest(pT.table, root, flags.sort), pT.maxwidth)
+		} else {
+			printTable(pT, descs)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// sortByPcpu orders the table busiest-first, the way top(1) defaults to,
+// using whichever %CPU Refresh most recently computed for each process.
+func sortByPcpu(table []Process) {
+	sort.SliceStable(table, func(i, j int) bool {
+		return pcpuValue(&table[i]) > pcpuValue(&table[j])
+	})
+}
+
+func pcpuValue(p *Process) float64 {
+	pct := p.deltaPcpu
+	if pct == "" {
+		pct = cpuPercent(*p)
+	}
+	var v float64
+	fmt.Sscanf(pct, "%f", &v)
+	return v
+}